@@ -1,37 +1,71 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/format"
 	"go/parser"
 	"go/token"
+	"hash/fnv"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input.go> <num_files>\n", os.Args[0])
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		if err := runMerge(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	group := flag.String("group", "count", "split strategy: count (even function count per file) or receiver (keep methods with their receiver type)")
+	balance := flag.String("balance", "count", "how to distribute functions across files under --group=count: count (equal function count, default) or cyclo (equal cyclomatic complexity)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--group=count|receiver] [--balance=count|cyclo] <input.go> <num_files>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s merge <dir> <output.go>\n", os.Args[0])
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
-	numFiles, err := strconv.Atoi(os.Args[2])
+	inputFile := args[0]
+	numFiles, err := strconv.Atoi(args[1])
 	if err != nil || numFiles <= 0 {
 		fmt.Fprintf(os.Stderr, "Error: num_files must be a positive integer\n")
 		os.Exit(1)
 	}
 
-	if err := splitGoFile(inputFile, numFiles); err != nil {
+	if *group != "count" && *group != "receiver" {
+		fmt.Fprintf(os.Stderr, "Error: --group must be \"count\" or \"receiver\"\n")
+		os.Exit(1)
+	}
+
+	if *balance != "count" && *balance != "cyclo" {
+		fmt.Fprintf(os.Stderr, "Error: --balance must be \"count\" or \"cyclo\"\n")
+		os.Exit(1)
+	}
+
+	if err := splitGoFile(inputFile, numFiles, *group, *balance); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func splitGoFile(inputFile string, numFiles int) error {
+func splitGoFile(inputFile string, numFiles int, group, balance string) error {
 	fset := token.NewFileSet()
 	node, err := parser.ParseFile(fset, inputFile, nil, parser.ParseComments)
 	if err != nil {
@@ -41,6 +75,7 @@ func splitGoFile(inputFile string, numFiles int) error {
 	packageName := node.Name.Name
 	imports := extractImports(node)
 	typeDecls := extractTypeDecls(node)
+	valueDecls := extractValueDecls(node)
 	functions := extractFunctions(node)
 
 	if len(functions) == 0 {
@@ -50,40 +85,395 @@ func splitGoFile(inputFile string, numFiles int) error {
 	baseFileName := strings.TrimSuffix(filepath.Base(inputFile), ".go")
 	outputDir := filepath.Dir(inputFile)
 
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory of %s: %v", inputFile, err)
+	}
+
 	existingFiles, err := getExistingFiles(outputDir)
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %v", err)
 	}
 
+	if group == "receiver" {
+		return splitByReceiver(fset, packageName, imports, typeDecls, valueDecls, functions, baseFileName, outputDir, absOutputDir, existingFiles, numFiles)
+	}
+
+	var functionBuckets [][]*ast.FuncDecl
+	if balance == "cyclo" {
+		functionBuckets = splitFunctionsByComplexity(functions, numFiles)
+	} else {
+		functionBuckets = splitFunctionsByCount(functions, numFiles)
+	}
+
+	valueDeclFile := assignValueDeclsByFuncBuckets(valueDecls, functionBuckets)
+
+	usedNames := make(map[string]bool)
+
+	for i, funcsForFile := range functionBuckets {
+		var typesForFile []*ast.GenDecl
+		if i == 0 {
+			typesForFile = typeDecls
+		}
+
+		var valuesForFile []*ast.GenDecl
+		for _, vd := range valueDecls {
+			if valueDeclFile[vd] == i {
+				valuesForFile = append(valuesForFile, vd)
+			}
+		}
+
+		suffix := generateUniqueFilenameSuffix(funcsForFile, typesForFile, valuesForFile, baseFileName, existingFiles, usedNames)
+		usedNames[suffix] = true
+		outputFile := filepath.Join(outputDir, fmt.Sprintf("%s_%s.go", baseFileName, suffix))
+
+		requiredImports := analyzeRequiredImports(fset, funcsForFile, typesForFile, valuesForFile, imports, absOutputDir)
+		if i == 0 {
+			requiredImports = append(requiredImports, sideEffectImports(imports)...)
+		}
+
+		if err := writeGoFile(outputFile, packageName, requiredImports, typesForFile, valuesForFile, funcsForFile, fset); err != nil {
+			return fmt.Errorf("failed to write %s: %v", outputFile, err)
+		}
+		fmt.Printf("Created: %s\n", outputFile)
+	}
+
+	return nil
+}
+
+// splitFunctionsByCount is the original, default split: numFiles buckets of
+// roughly equal function count, in declaration order.
+func splitFunctionsByCount(functions []*ast.FuncDecl, numFiles int) [][]*ast.FuncDecl {
 	funcsPerFile := len(functions) / numFiles
 	if len(functions)%numFiles != 0 {
 		funcsPerFile++
 	}
 
-	usedNames := make(map[string]bool)
-
+	var buckets [][]*ast.FuncDecl
 	for i := 0; i < numFiles; i++ {
 		start := i * funcsPerFile
+		if start >= len(functions) {
+			break
+		}
 		end := start + funcsPerFile
 		if end > len(functions) {
 			end = len(functions)
 		}
-		if start >= len(functions) {
-			break
+		buckets = append(buckets, functions[start:end])
+	}
+	return buckets
+}
+
+// splitFunctionsByComplexity distributes functions so each bucket carries
+// roughly equal total McCabe complexity, via greedy longest-processing-time
+// bin-packing: largest functions first, each to the currently lightest file.
+func splitFunctionsByComplexity(functions []*ast.FuncDecl, numFiles int) [][]*ast.FuncDecl {
+	origIndex := make(map[*ast.FuncDecl]int, len(functions))
+	for i, fn := range functions {
+		origIndex[fn] = i
+	}
+
+	ordered := make([]*ast.FuncDecl, len(functions))
+	copy(ordered, functions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return cyclomaticComplexity(ordered[i]) > cyclomaticComplexity(ordered[j])
+	})
+
+	buckets := make([][]*ast.FuncDecl, numFiles)
+	totals := make([]int, numFiles)
+	for _, fn := range ordered {
+		smallest := 0
+		for i := 1; i < numFiles; i++ {
+			if totals[i] < totals[smallest] {
+				smallest = i
+			}
+		}
+		buckets[smallest] = append(buckets[smallest], fn)
+		totals[smallest] += cyclomaticComplexity(fn)
+	}
+
+	var nonEmpty [][]*ast.FuncDecl
+	for _, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		sort.Slice(bucket, func(i, j int) bool { return origIndex[bucket[i]] < origIndex[bucket[j]] })
+		nonEmpty = append(nonEmpty, bucket)
+	}
+	return nonEmpty
+}
+
+// cyclomaticComplexity computes McCabe complexity for fn: starts at 1, plus 1
+// for each branch point (if/for/range/non-default case or comm clause) and
+// each short-circuit operator, since those are the decision points that add
+// an independent path through the function.
+func cyclomaticComplexity(fn *ast.FuncDecl) int {
+	complexity := 1
+	if fn.Body == nil {
+		return complexity
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.IfStmt:
+			complexity++
+		case *ast.ForStmt:
+			complexity++
+		case *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if node.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if node.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if node.Op == token.LAND || node.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+
+	return complexity
+}
+
+// assignValueDeclsByFuncBuckets colocates each value decl with the file of
+// the first function bucket that references it, else file 0, against
+// arbitrary function buckets rather than contiguous ranges, so it works for
+// both the count and cyclo balance modes.
+func assignValueDeclsByFuncBuckets(valueDecls []*ast.GenDecl, buckets [][]*ast.FuncDecl) map[*ast.GenDecl]int {
+	fileOf := make(map[*ast.GenDecl]int, len(valueDecls))
+
+	for _, vd := range valueDecls {
+		names := declaredNames(vd)
+		fileOf[vd] = 0
+
+	bucketLoop:
+		for i, bucket := range buckets {
+			for _, fn := range bucket {
+				if referencesAny(fn, names) {
+					fileOf[vd] = i
+					break bucketLoop
+				}
+			}
+		}
+	}
+
+	return fileOf
+}
+
+// declGroupNode is one node in the receiver dependency graph: either a
+// package-level type block or a single function/method.
+type declGroupNode struct {
+	origIndex int
+	typeDecl  *ast.GenDecl
+	funcDecl  *ast.FuncDecl
+}
+
+func (n declGroupNode) astNode() ast.Node {
+	if n.typeDecl != nil {
+		return n.typeDecl
+	}
+	return n.funcDecl
+}
+
+func (n declGroupNode) names() map[string]bool {
+	if n.typeDecl != nil {
+		names := make(map[string]bool)
+		for _, spec := range n.typeDecl.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok {
+				names[ts.Name.Name] = true
+			}
+		}
+		return names
+	}
+	return map[string]bool{n.funcDecl.Name.Name: true}
+}
+
+// receiverTypeName returns the unqualified type name n is a method of, or ""
+// if n isn't a method.
+func (n declGroupNode) receiverTypeName() string {
+	if n.funcDecl == nil || n.funcDecl.Recv == nil || len(n.funcDecl.Recv.List) == 0 {
+		return ""
+	}
+	expr := n.funcDecl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// groupByDependency builds a dependency graph over the package's types and
+// functions - edges are identifier references discovered via ast.Inspect plus
+// the method-to-receiver-type link - and returns its connected components.
+// Every method lands in the same component as the type it has a receiver on.
+func groupByDependency(typeDecls []*ast.GenDecl, functions []*ast.FuncDecl) [][]declGroupNode {
+	var nodes []declGroupNode
+	for _, td := range typeDecls {
+		nodes = append(nodes, declGroupNode{origIndex: len(nodes), typeDecl: td})
+	}
+	for _, fn := range functions {
+		nodes = append(nodes, declGroupNode{origIndex: len(nodes), funcDecl: fn})
+	}
+
+	nameToIndex := make(map[string]int)
+	for i, n := range nodes {
+		for name := range n.names() {
+			nameToIndex[name] = i
+		}
+	}
+
+	parent := make([]int, len(nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[ra] = rb
+		}
+	}
+
+	for i, n := range nodes {
+		if recv := n.receiverTypeName(); recv != "" {
+			if j, ok := nameToIndex[recv]; ok {
+				union(i, j)
+			}
+		}
+		ast.Inspect(n.astNode(), func(x ast.Node) bool {
+			if ident, ok := x.(*ast.Ident); ok {
+				if j, ok := nameToIndex[ident.Name]; ok && j != i {
+					union(i, j)
+				}
+			}
+			return true
+		})
+	}
+
+	byRoot := make(map[int][]declGroupNode)
+	for i, n := range nodes {
+		root := find(i)
+		byRoot[root] = append(byRoot[root], n)
+	}
+
+	var components [][]declGroupNode
+	for _, g := range byRoot {
+		components = append(components, g)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0].origIndex < components[j][0].origIndex
+	})
+	return components
+}
+
+// partitionGroups bin-packs dependency components into numFiles buckets with
+// a greedy longest-processing-time pass: largest components first, each
+// assigned to whichever bucket currently holds the least.
+func partitionGroups(components [][]declGroupNode, numFiles int) [][]declGroupNode {
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+
+	buckets := make([][]declGroupNode, numFiles)
+	totals := make([]int, numFiles)
+	for _, component := range components {
+		smallest := 0
+		for i := 1; i < numFiles; i++ {
+			if totals[i] < totals[smallest] {
+				smallest = i
+			}
+		}
+		buckets[smallest] = append(buckets[smallest], component...)
+		totals[smallest] += len(component)
+	}
+
+	for _, bucket := range buckets {
+		sort.Slice(bucket, func(i, j int) bool { return bucket[i].origIndex < bucket[j].origIndex })
+	}
+	return buckets
+}
+
+// assignValueDeclsToBuckets applies the same placement policy as
+// assignValueDeclsByFuncBuckets, but against receiver-mode buckets instead of
+// contiguous function ranges.
+func assignValueDeclsToBuckets(valueDecls []*ast.GenDecl, buckets [][]declGroupNode) map[*ast.GenDecl]int {
+	fileOf := make(map[*ast.GenDecl]int, len(valueDecls))
+
+	for _, vd := range valueDecls {
+		names := declaredNames(vd)
+		fileOf[vd] = 0
+
+	bucketLoop:
+		for i, bucket := range buckets {
+			for _, n := range bucket {
+				if n.funcDecl != nil && referencesAny(n.funcDecl, names) {
+					fileOf[vd] = i
+					break bucketLoop
+				}
+			}
+		}
+	}
+
+	return fileOf
+}
+
+// splitByReceiver implements --group=receiver: decls are clustered by
+// reference/receiver dependency rather than sliced by position, so a type
+// and all of its methods always land in the same output file.
+func splitByReceiver(fset *token.FileSet, packageName string, imports []*ast.ImportSpec, typeDecls, valueDecls []*ast.GenDecl, functions []*ast.FuncDecl, baseFileName, outputDir, absOutputDir string, existingFiles map[string]bool, numFiles int) error {
+	components := groupByDependency(typeDecls, functions)
+	buckets := partitionGroups(components, numFiles)
+	valueDeclFile := assignValueDeclsToBuckets(valueDecls, buckets)
+
+	usedNames := make(map[string]bool)
+	sideEffectsAttached := false
+
+	for i, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
 		}
 
 		var typesForFile []*ast.GenDecl
-		if i == 0 {
-			typesForFile = typeDecls
+		var functionsForFile []*ast.FuncDecl
+		for _, n := range bucket {
+			if n.typeDecl != nil {
+				typesForFile = append(typesForFile, n.typeDecl)
+			} else {
+				functionsForFile = append(functionsForFile, n.funcDecl)
+			}
+		}
+
+		var valuesForFile []*ast.GenDecl
+		for _, vd := range valueDecls {
+			if valueDeclFile[vd] == i {
+				valuesForFile = append(valuesForFile, vd)
+			}
 		}
 
-		suffix := generateUniqueFilenameSuffix(functions[start:end], i == 0, baseFileName, existingFiles, usedNames)
+		suffix := generateUniqueFilenameSuffix(functionsForFile, typesForFile, valuesForFile, baseFileName, existingFiles, usedNames)
 		usedNames[suffix] = true
 		outputFile := filepath.Join(outputDir, fmt.Sprintf("%s_%s.go", baseFileName, suffix))
-		
-		requiredImports := analyzeRequiredImports(functions[start:end], typesForFile, imports)
-		
-		if err := writeGoFile(outputFile, packageName, requiredImports, typesForFile, functions[start:end], fset); err != nil {
+
+		requiredImports := analyzeRequiredImports(fset, functionsForFile, typesForFile, valuesForFile, imports, absOutputDir)
+		if !sideEffectsAttached {
+			requiredImports = append(requiredImports, sideEffectImports(imports)...)
+			sideEffectsAttached = true
+		}
+
+		if err := writeGoFile(outputFile, packageName, requiredImports, typesForFile, valuesForFile, functionsForFile, fset); err != nil {
 			return fmt.Errorf("failed to write %s: %v", outputFile, err)
 		}
 		fmt.Printf("Created: %s\n", outputFile)
@@ -92,6 +482,145 @@ func splitGoFile(inputFile string, numFiles int) error {
 	return nil
 }
 
+func runMerge(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s merge <dir> <output.go>", filepath.Base(os.Args[0]))
+	}
+	return mergeGoFiles(args[0], args[1])
+}
+
+// mergeGoFiles is the inverse of splitGoFile: it parses every non-test .go
+// file in dir that shares a package name, unions their decls, and writes a
+// single file. _test.go files are skipped, since their tests and the
+// testing import belong with the test files, not the merged production
+// file. It reuses the same decl extraction and import-pruning logic
+// splitGoFile uses, so round-tripping split -> merge reproduces an
+// equivalent package.
+func mergeGoFiles(dir, outputFile string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory %s: %v", dir, err)
+	}
+
+	fset := token.NewFileSet()
+	var packageName string
+	var allImports []*ast.ImportSpec
+	var allTypeDecls []*ast.GenDecl
+	var allValueDecls []*ast.GenDecl
+	var allFunctions []*ast.FuncDecl
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		node, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		if packageName == "" {
+			packageName = node.Name.Name
+		} else if node.Name.Name != packageName {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: package %s does not match %s\n", path, node.Name.Name, packageName)
+			continue
+		}
+
+		allImports = append(allImports, extractImports(node)...)
+		allTypeDecls = append(allTypeDecls, extractTypeDecls(node)...)
+		allValueDecls = append(allValueDecls, extractValueDecls(node)...)
+		allFunctions = append(allFunctions, extractFunctions(node)...)
+	}
+
+	if packageName == "" {
+		return fmt.Errorf("no Go files found in %s", dir)
+	}
+
+	imports := unionImports(allImports)
+
+	sort.Slice(allTypeDecls, func(i, j int) bool {
+		return typeDeclName(allTypeDecls[i]) < typeDeclName(allTypeDecls[j])
+	})
+
+	var varDecls, constDecls []*ast.GenDecl
+	for _, vd := range allValueDecls {
+		if vd.Tok == token.CONST {
+			constDecls = append(constDecls, vd)
+		} else {
+			varDecls = append(varDecls, vd)
+		}
+	}
+	sort.Slice(varDecls, func(i, j int) bool { return valueDeclName(varDecls[i]) < valueDeclName(varDecls[j]) })
+	sort.Slice(constDecls, func(i, j int) bool { return valueDeclName(constDecls[i]) < valueDeclName(constDecls[j]) })
+	orderedValueDecls := append(append([]*ast.GenDecl{}, varDecls...), constDecls...)
+
+	sort.Slice(allFunctions, func(i, j int) bool { return allFunctions[i].Name.Name < allFunctions[j].Name.Name })
+
+	requiredImports := analyzeRequiredImports(fset, allFunctions, allTypeDecls, orderedValueDecls, imports, absDir)
+	requiredImports = append(requiredImports, sideEffectImports(imports)...)
+
+	if err := writeGoFile(outputFile, packageName, requiredImports, allTypeDecls, orderedValueDecls, allFunctions, fset); err != nil {
+		return fmt.Errorf("failed to write %s: %v", outputFile, err)
+	}
+	fmt.Printf("Created: %s\n", outputFile)
+	return nil
+}
+
+// unionImports dedupes import specs by path, preferring an explicit alias
+// when present, and warns on conflicting aliases for the same path.
+func unionImports(imports []*ast.ImportSpec) []*ast.ImportSpec {
+	byPath := make(map[string]*ast.ImportSpec)
+	var order []string
+
+	for _, imp := range imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		existing, ok := byPath[path]
+		if !ok {
+			byPath[path] = imp
+			order = append(order, path)
+			continue
+		}
+
+		switch {
+		case existing.Name == nil && imp.Name != nil:
+			byPath[path] = imp
+		case existing.Name != nil && imp.Name != nil && existing.Name.Name != imp.Name.Name:
+			fmt.Fprintf(os.Stderr, "Warning: conflicting aliases for %q: %q vs %q, keeping %q\n",
+				path, existing.Name.Name, imp.Name.Name, existing.Name.Name)
+		}
+	}
+
+	result := make([]*ast.ImportSpec, 0, len(order))
+	for _, path := range order {
+		result = append(result, byPath[path])
+	}
+	return result
+}
+
+func typeDeclName(genDecl *ast.GenDecl) string {
+	for _, spec := range genDecl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok {
+			return ts.Name.Name
+		}
+	}
+	return ""
+}
+
+func valueDeclName(genDecl *ast.GenDecl) string {
+	for _, spec := range genDecl.Specs {
+		if vs, ok := spec.(*ast.ValueSpec); ok && len(vs.Names) > 0 {
+			return vs.Names[0].Name
+		}
+	}
+	return ""
+}
+
 func extractImports(node *ast.File) []*ast.ImportSpec {
 	var imports []*ast.ImportSpec
 	for _, decl := range node.Decls {
@@ -116,6 +645,55 @@ func extractTypeDecls(node *ast.File) []*ast.GenDecl {
 	return typeDecls
 }
 
+func extractValueDecls(node *ast.File) []*ast.GenDecl {
+	var valueDecls []*ast.GenDecl
+	for _, decl := range node.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && (genDecl.Tok == token.VAR || genDecl.Tok == token.CONST) {
+			valueDecls = append(valueDecls, genDecl)
+		}
+	}
+	return valueDecls
+}
+
+// sideEffectImports returns blank (_) and dot (.) imports, which must survive
+// even though analyzeRequiredImports' identifier matching can never see them used.
+func sideEffectImports(imports []*ast.ImportSpec) []*ast.ImportSpec {
+	var side []*ast.ImportSpec
+	for _, imp := range imports {
+		if imp.Name != nil && (imp.Name.Name == "_" || imp.Name.Name == ".") {
+			side = append(side, imp)
+		}
+	}
+	return side
+}
+
+func declaredNames(genDecl *ast.GenDecl) map[string]bool {
+	names := make(map[string]bool)
+	for _, spec := range genDecl.Specs {
+		if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+			for _, name := range valueSpec.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+func referencesAny(fn *ast.FuncDecl, names map[string]bool) bool {
+	found := false
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok && names[ident.Name] {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
 func extractFunctions(node *ast.File) []*ast.FuncDecl {
 	var functions []*ast.FuncDecl
 	for _, decl := range node.Decls {
@@ -126,7 +704,7 @@ func extractFunctions(node *ast.File) []*ast.FuncDecl {
 	return functions
 }
 
-func writeGoFile(filename, packageName string, imports []*ast.ImportSpec, typeDecls []*ast.GenDecl, functions []*ast.FuncDecl, fset *token.FileSet) error {
+func writeGoFile(filename, packageName string, imports []*ast.ImportSpec, typeDecls, valueDecls []*ast.GenDecl, functions []*ast.FuncDecl, fset *token.FileSet) error {
 	file := &ast.File{
 		Name: &ast.Ident{Name: packageName},
 	}
@@ -145,6 +723,10 @@ func writeGoFile(filename, packageName string, imports []*ast.ImportSpec, typeDe
 		file.Decls = append(file.Decls, typeDecl)
 	}
 
+	for _, valueDecl := range valueDecls {
+		file.Decls = append(file.Decls, valueDecl)
+	}
+
 	for _, fn := range functions {
 		file.Decls = append(file.Decls, fn)
 	}
@@ -172,119 +754,241 @@ func getExistingFiles(dir string) (map[string]bool, error) {
 	return files, nil
 }
 
-func generateUniqueFilenameSuffix(functions []*ast.FuncDecl, hasTypes bool, baseFileName string, existingFiles map[string]bool, usedNames map[string]bool) string {
-	baseSuffix := generateFilenameSuffix(functions, hasTypes)
-	suffix := baseSuffix
-	
+// stopwords are common English words that carry no information about a
+// chunk's purpose and so are never good filename suffixes.
+var stopwords = map[string]bool{
+	"the": true, "a": true, "an": true, "is": true, "are": true, "was": true,
+	"were": true, "of": true, "to": true, "in": true, "on": true, "for": true,
+	"and": true, "or": true, "it": true, "its": true, "this": true, "that": true,
+	"these": true, "those": true, "as": true, "by": true, "with": true, "from": true,
+	"at": true, "be": true, "which": true, "if": true, "then": true, "than": true,
+	"so": true, "not": true, "no": true, "can": true, "will": true, "would": true,
+	"should": true, "may": true, "might": true, "must": true, "also": true, "but": true,
+	"into": true, "when": true, "returns": true, "return": true, "use": true, "used": true,
+}
+
+// goKeywords are excluded alongside stopwords since a reserved word can
+// appear in doc comments (e.g. "for each X") without describing the chunk.
+var goKeywords = map[string]bool{
+	"break": true, "default": true, "func": true, "interface": true, "select": true,
+	"case": true, "defer": true, "go": true, "map": true, "struct": true,
+	"chan": true, "else": true, "goto": true, "package": true, "switch": true,
+	"const": true, "fallthrough": true, "if": true, "range": true, "type": true,
+	"continue": true, "for": true, "import": true, "return": true, "var": true,
+}
+
+// buildConstrainedSuffixes are words that must never be used as a filename
+// suffix on their own: "test" makes go build silently exclude the file from
+// the regular package, and a GOOS or GOARCH name gives it an implicit
+// platform build tag, so the file silently disappears everywhere else. Both
+// are entirely plausible doc-comment vocabulary (e.g. "linux", "arm64"), so
+// they're rejected the same way stopwords/goKeywords are.
+var buildConstrainedSuffixes = func() map[string]bool {
+	m := map[string]bool{"test": true}
+	for _, goos := range knownOS {
+		m[goos] = true
+	}
+	for _, goarch := range knownArch {
+		m[goarch] = true
+	}
+	return m
+}()
+
+var knownOS = []string{
+	"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos",
+	"ios", "js", "linux", "nacl", "netbsd", "openbsd", "plan9", "solaris",
+	"wasip1", "windows", "zos",
+}
+
+var knownArch = []string{
+	"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64",
+	"mips", "mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le",
+	"ppc", "ppc64", "ppc64le", "riscv", "riscv64", "s390", "s390x", "sparc",
+	"sparc64", "wasm",
+}
+
+// generateUniqueFilenameSuffix picks a filename suffix for a chunk, preferring
+// a word drawn from its own doc comments and falling back to a numbered
+// variant when that word collides with a file already on disk or already
+// chosen this run.
+func generateUniqueFilenameSuffix(functions []*ast.FuncDecl, typeDecls, valueDecls []*ast.GenDecl, baseFileName string, existingFiles map[string]bool, usedNames map[string]bool) string {
+	suffix := generateFilenameSuffix(functions, typeDecls, valueDecls, usedNames)
+
 	filename := fmt.Sprintf("%s_%s.go", baseFileName, suffix)
 	if !existingFiles[filename] && !usedNames[suffix] {
 		return suffix
 	}
-	
-	alternatives := []string{"core", "main", "base", "util", "helper", "common", "shared", "extra", "misc", "other", "new", "alt", "impl", "logic", "work", "task", "ops", "flow", "step", "part", "unit", "chunk", "block", "piece", "item", "elem", "node", "link", "path", "route", "view", "ctrl", "model", "data", "info", "meta", "config", "setup", "init", "boot", "start", "launch", "run", "exec", "proc", "action", "event", "state", "change", "update", "modify", "edit", "fix", "patch", "clean", "clear", "reset", "fresh", "quick", "fast", "slow", "temp", "local", "remote", "public", "private", "secure", "safe", "simple", "basic", "advanced", "custom", "special", "unique", "single", "multi", "batch", "group", "list", "set", "map", "tree", "graph", "queue", "stack", "buffer", "cache", "store", "load", "save", "fetch", "send", "recv", "sync", "async", "wait", "done", "ready", "active", "idle", "busy", "free", "open", "close", "lock", "unlock", "check", "test", "verify", "valid", "error", "warn", "debug", "trace", "log", "print", "show", "hide", "render", "draw", "build", "make", "craft", "forge", "shape", "form", "mold", "cast", "press", "push", "pull", "move", "shift", "slide", "jump", "skip", "next", "prev", "first", "last", "top", "bottom", "left", "right", "center", "middle", "inner", "outer", "upper", "lower", "high", "low", "big", "small", "large", "tiny", "huge", "mini", "full", "empty", "blank", "void", "null", "zero", "one", "two", "three", "many", "few", "some", "all", "none", "auto", "manual", "smart", "dumb", "cool", "warm", "hot", "cold", "wet", "dry", "soft", "hard", "light", "dark", "bright", "dim", "loud", "quiet", "fast", "slow", "old", "young", "rich", "poor", "true", "false", "good", "bad", "nice", "ugly", "clean", "dirty", "pure", "mixed", "solid", "liquid", "gas", "fire", "water", "earth", "air", "wood", "metal", "stone", "glass", "paper", "cloth", "rope", "wire", "pipe", "tube", "box", "bag", "cup", "bowl", "plate", "knife", "fork", "spoon", "tool", "gear", "part", "chip", "disk", "tape", "card", "key", "lock", "door", "window", "wall", "floor", "roof", "room", "house", "city", "town", "road", "street", "bridge", "river", "lake", "sea", "ocean", "mountain", "hill", "tree", "flower", "grass", "leaf", "seed", "fruit", "root", "branch", "trunk", "bark", "wood", "forest", "field", "farm", "garden", "park", "zoo", "museum", "school", "library", "store", "shop", "market", "bank", "office", "factory", "lab", "studio", "theater", "cinema", "restaurant", "cafe", "hotel", "hospital", "church", "temple", "castle", "tower", "bridge", "tunnel", "cave", "valley", "desert", "island", "beach", "shore", "coast", "port", "harbor", "dock", "ship", "boat", "plane", "train", "car", "bike", "truck", "bus", "taxi", "rocket", "satellite", "star", "moon", "sun", "planet", "comet", "meteor", "galaxy", "universe", "space", "time", "year", "month", "week", "day", "hour", "minute", "second", "moment", "instant", "flash", "spark", "flame", "smoke", "cloud", "rain", "snow", "ice", "frost", "dew", "mist", "fog", "wind", "storm", "thunder", "lightning", "rainbow", "shadow", "light", "beam", "ray", "wave", "sound", "music", "song", "voice", "word", "letter", "number", "symbol", "sign", "mark", "dot", "line", "curve", "circle", "square", "triangle", "diamond", "heart", "star", "cross", "arrow", "spiral", "wave", "grid", "mesh", "net", "web", "thread", "string", "rope", "chain", "link", "bond", "tie", "knot", "loop", "ring", "band", "strip", "belt", "strap", "handle", "grip", "hold", "grasp", "touch", "feel", "sense", "see", "look", "watch", "view", "scan", "search", "find", "seek", "hunt", "track", "follow", "lead", "guide", "direct", "point", "aim", "target", "goal", "end", "finish", "complete", "done", "over", "final", "last", "stop", "halt", "pause", "break", "rest", "sleep", "wake", "rise", "fall", "drop", "lift", "raise", "lower", "turn", "spin", "rotate", "twist", "bend", "fold", "open", "close", "shut", "seal", "break", "crack", "split", "join", "merge", "unite", "divide", "separate", "cut", "slice", "chop", "tear", "rip", "grab", "catch", "throw", "toss", "cast", "shoot", "fire", "blast", "explode", "crash", "smash", "hit", "strike", "punch", "kick", "stomp", "step", "walk", "run", "jog", "sprint", "rush", "hurry", "slow", "crawl", "climb", "jump", "leap", "hop", "skip", "dance", "swim", "dive", "float", "fly", "soar", "glide", "drift", "flow", "stream", "rush", "pour", "drip", "leak", "spill", "splash", "spray", "mist", "dust", "powder", "grain", "sand", "dirt", "mud", "clay", "rock", "stone", "pebble", "boulder", "crystal", "gem", "gold", "silver", "copper", "iron", "steel", "brass", "bronze", "tin", "lead", "zinc", "chrome", "nickel", "cobalt", "carbon", "silicon", "oxygen", "hydrogen", "nitrogen", "helium", "neon", "argon", "mercury", "venus", "mars", "jupiter", "saturn", "uranus", "neptune", "pluto"}
-	
-	for _, alt := range alternatives {
-		testSuffix := alt
+
+	for i := 1; i <= 999; i++ {
+		testSuffix := fmt.Sprintf("%s%d", suffix, i)
 		filename = fmt.Sprintf("%s_%s.go", baseFileName, testSuffix)
 		if !existingFiles[filename] && !usedNames[testSuffix] {
 			return testSuffix
 		}
 	}
-	
-	for i := 1; i <= 999; i++ {
-		testSuffix := fmt.Sprintf("%s%d", baseSuffix, i)
-		filename = fmt.Sprintf("%s_%s.go", baseFileName, testSuffix)
-		if !existingFiles[filename] && !usedNames[testSuffix] {
-			return testSuffix
+
+	return fmt.Sprintf("%s%d", suffix, 999)
+}
+
+// generateFilenameSuffix picks the chunk's single most descriptive word: the
+// highest-term-frequency word across the leading doc comments of its
+// FuncDecls/GenDecls that isn't a stopword, a Go keyword, a name that would
+// give the output file an implicit build constraint, or a suffix already
+// chosen for another file in this run. When no doc comment yields a usable
+// word, it falls back to a short hash of the chunk's sorted decl names.
+func generateFilenameSuffix(functions []*ast.FuncDecl, typeDecls, valueDecls []*ast.GenDecl, usedSuffixes map[string]bool) string {
+	termFreq := make(map[string]int)
+
+	addDoc := func(doc *ast.CommentGroup) {
+		if doc == nil {
+			return
 		}
+		for _, word := range strings.Fields(doc.Text()) {
+			term := normalizeDocTerm(word)
+			if term == "" || stopwords[term] || goKeywords[term] || buildConstrainedSuffixes[term] {
+				continue
+			}
+			termFreq[term]++
+		}
+	}
+
+	for _, fn := range functions {
+		addDoc(fn.Doc)
 	}
-	
-	return fmt.Sprintf("%s%d", baseSuffix, 999)
+	for _, td := range typeDecls {
+		addDoc(td.Doc)
+	}
+	for _, vd := range valueDecls {
+		addDoc(vd.Doc)
+	}
+
+	if best := highestUniqueTerm(termFreq, usedSuffixes); best != "" {
+		return best
+	}
+
+	return hashDeclNames(functions, typeDecls, valueDecls)
 }
 
-func generateFilenameSuffix(functions []*ast.FuncDecl, hasTypes bool) string {
-	if hasTypes {
-		return "types"
+// normalizeDocTerm strips leading/trailing punctuation and lower-cases word,
+// rejecting anything too short to be a meaningful filename suffix.
+func normalizeDocTerm(word string) string {
+	trimmed := strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(trimmed) < 3 {
+		return ""
 	}
-	
-	if len(functions) == 0 {
-		return "empty"
+	for _, r := range trimmed {
+		if !unicode.IsLetter(r) {
+			return ""
+		}
+	}
+	return strings.ToLower(trimmed)
+}
+
+// highestUniqueTerm returns the most frequent term not already used as a
+// suffix, breaking ties alphabetically so the choice is deterministic.
+func highestUniqueTerm(termFreq map[string]int, usedSuffixes map[string]bool) string {
+	var best string
+	bestFreq := 0
+	for term, freq := range termFreq {
+		if usedSuffixes[term] {
+			continue
+		}
+		if freq > bestFreq || (freq == bestFreq && term < best) {
+			best = term
+			bestFreq = freq
+		}
 	}
-	
-	funcNames := make([]string, 0, len(functions))
+	return best
+}
+
+// hashDeclNames is the fallback suffix when no doc comment yields a usable
+// word: a short hash of the chunk's sorted decl names, so the same chunk
+// always gets the same suffix across runs.
+func hashDeclNames(functions []*ast.FuncDecl, typeDecls, valueDecls []*ast.GenDecl) string {
+	var names []string
 	for _, fn := range functions {
 		if fn.Name != nil {
-			funcNames = append(funcNames, strings.ToLower(fn.Name.Name))
+			names = append(names, fn.Name.Name)
 		}
 	}
-	
-	if len(funcNames) == 0 {
-		return "funcs"
+	for _, td := range typeDecls {
+		names = append(names, typeDeclName(td))
 	}
-	
-	keywords := []string{"parse", "generate", "create", "process", "handle", "convert", "build", "render", "execute", "validate", "format", "transform", "encode", "decode", "load", "save", "write", "read", "get", "set", "add", "remove", "update", "delete", "find", "search", "filter", "sort", "merge", "split", "join", "copy", "move", "clean", "init", "start", "stop", "run", "exec", "call", "invoke", "apply", "map", "reduce", "collect", "stream", "buffer", "cache", "store", "fetch", "send", "receive", "upload", "download", "compress", "extract", "zip", "unzip", "backup", "restore", "sync", "async", "wait", "notify", "signal", "lock", "unlock", "open", "close", "connect", "disconnect", "bind", "unbind", "listen", "serve", "request", "response", "query", "insert", "select", "count", "sum", "max", "min", "avg", "group", "order", "limit", "offset", "page", "chunk", "batch", "parallel", "serial", "concurrent", "thread", "worker", "job", "task", "queue", "stack", "list", "array", "map", "set", "tree", "graph", "node", "edge", "path", "route", "url", "uri", "link", "ref", "ptr", "addr", "size", "len", "cap", "empty", "full", "contains", "exists", "valid", "invalid", "ok", "error", "warn", "info", "debug", "trace", "log", "print", "show", "display", "render", "draw", "paint", "color", "style", "theme", "skin", "layout", "align", "position", "size", "resize", "scale", "zoom", "pan", "scroll", "drag", "drop", "click", "hover", "focus", "blur", "select", "deselect", "toggle", "switch", "enable", "disable", "show", "hide", "visible", "invisible", "active", "inactive", "on", "off", "true", "false", "yes", "no", "ok", "cancel", "submit", "reset", "clear", "clean", "flush", "purge", "refresh", "reload", "restart", "resume", "pause", "play", "record", "replay", "undo", "redo", "cut", "copy", "paste", "clone", "duplicate", "mirror", "reflect", "invert", "reverse", "flip", "rotate", "shift", "move", "slide", "fade", "animate", "transition", "effect", "filter", "mask", "overlay", "background", "foreground", "layer", "depth", "level", "priority", "weight", "rank", "score", "rate", "ratio", "percent", "fraction", "decimal", "integer", "float", "double", "string", "char", "byte", "bit", "word", "line", "paragraph", "section", "chapter", "page", "document", "file", "folder", "directory", "path", "name", "title", "label", "tag", "attr", "prop", "field", "column", "row", "cell", "table", "grid", "matrix", "vector", "point", "coord", "pos", "loc", "place", "spot", "area", "region", "zone", "space", "room", "box", "container", "wrapper", "holder", "frame", "border", "edge", "corner", "side", "top", "bottom", "left", "right", "center", "middle", "inner", "outer", "inside", "outside", "before", "after", "first", "last", "next", "prev", "current", "new", "old", "temp", "tmp", "backup", "orig", "copy", "clone", "draft", "final", "test", "demo", "sample", "example", "template", "pattern", "model", "schema", "struct", "class", "type", "kind", "sort", "category", "group", "team", "user", "admin", "guest", "public", "private", "secure", "safe", "unsafe", "danger", "risk", "warn", "alert", "notice", "message", "text", "content", "data", "info", "meta", "config", "setting", "option", "param", "arg", "value", "result", "output", "input", "source", "target", "dest", "from", "to", "via", "through", "by", "with", "without", "using", "based", "upon", "over", "under", "above", "below", "between", "among", "within", "outside", "beyond", "across", "along", "around", "through", "during", "while", "until", "since", "before", "after", "when", "where", "what", "who", "why", "how", "which", "whose", "whom", "that", "this", "these", "those", "such", "same", "other", "another", "each", "every", "all", "any", "some", "none", "nothing", "something", "anything", "everything", "somewhere", "anywhere", "everywhere", "nowhere", "someone", "anyone", "everyone", "no one"}
-	
-	for _, keyword := range keywords {
-		for _, name := range funcNames {
-			if strings.Contains(name, keyword) {
-				return keyword
-			}
-		}
+	for _, vd := range valueDecls {
+		names = append(names, valueDeclName(vd))
 	}
-	
-	if len(funcNames[0]) > 0 {
-		return strings.ToLower(string(funcNames[0][0]))
+
+	if len(names) == 0 {
+		return "empty"
 	}
-	
-	return "funcs"
+	sort.Strings(names)
+
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(names, ",")))
+	return fmt.Sprintf("x%x", h.Sum32())
 }
 
-func analyzeRequiredImports(functions []*ast.FuncDecl, typeDecls []*ast.GenDecl, allImports []*ast.ImportSpec) []*ast.ImportSpec {
-	usedIdentifiers := make(map[string]bool)
-	
-	for _, fn := range functions {
-		ast.Inspect(fn, func(n ast.Node) bool {
-			switch node := n.(type) {
-			case *ast.SelectorExpr:
-				if ident, ok := node.X.(*ast.Ident); ok {
-					usedIdentifiers[ident.Name] = true
-				}
-			case *ast.Ident:
-				usedIdentifiers[node.Name] = true
-			}
-			return true
-		})
-	}
-	
+// analyzeRequiredImports decides which of allImports the given chunk actually
+// needs. It defers to astutil.UsesImport for the identifier resolution rather
+// than matching raw identifiers against a path segment, so aliased imports
+// (foo "bar/baz") and packages whose declared name differs from their import
+// path's last segment (gopkg.in/yaml.v3 -> package yaml) are handled correctly.
+// Blank and dot imports are excluded here; splitGoFile attaches those to file
+// 0 unconditionally since their whole purpose is invisible to identifier use.
+func analyzeRequiredImports(fset *token.FileSet, functions []*ast.FuncDecl, typeDecls, valueDecls []*ast.GenDecl, allImports []*ast.ImportSpec, srcDir string) []*ast.ImportSpec {
+	scratch := &ast.File{Name: &ast.Ident{Name: "_"}}
 	for _, typeDecl := range typeDecls {
-		ast.Inspect(typeDecl, func(n ast.Node) bool {
-			switch node := n.(type) {
-			case *ast.SelectorExpr:
-				if ident, ok := node.X.(*ast.Ident); ok {
-					usedIdentifiers[ident.Name] = true
-				}
-			case *ast.Ident:
-				usedIdentifiers[node.Name] = true
-			}
-			return true
-		})
+		scratch.Decls = append(scratch.Decls, typeDecl)
+	}
+	for _, valueDecl := range valueDecls {
+		scratch.Decls = append(scratch.Decls, valueDecl)
+	}
+	for _, fn := range functions {
+		scratch.Decls = append(scratch.Decls, fn)
 	}
-	
+
 	var requiredImports []*ast.ImportSpec
 	for _, imp := range allImports {
-		var importName string
-		if imp.Name != nil {
-			importName = imp.Name.Name
-		} else {
-			importPath := strings.Trim(imp.Path.Value, `"`)
-			parts := strings.Split(importPath, "/")
-			importName = parts[len(parts)-1]
+		if imp.Name != nil && (imp.Name.Name == "_" || imp.Name.Name == ".") {
+			continue
+		}
+
+		path := strings.Trim(imp.Path.Value, `"`)
+		probe := &ast.ImportSpec{Path: imp.Path, Name: imp.Name}
+		if probe.Name == nil {
+			name, ok := resolvePackageName(path, srcDir)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Warning: could not resolve package name for %q; guessing %q from its path, which may drop this import from the output\n", path, name)
+			}
+			probe.Name = &ast.Ident{Name: name}
 		}
-		
-		if usedIdentifiers[importName] {
+		scratch.Imports = []*ast.ImportSpec{probe}
+
+		if astutil.UsesImport(scratch, path) {
 			requiredImports = append(requiredImports, imp)
 		}
 	}
-	
+
 	return requiredImports
-}
\ No newline at end of file
+}
+
+// resolvePackageName returns the name the package at importPath declares
+// itself under, so yaml.v3-style paths resolve to the identifier code
+// actually uses (yaml) rather than the last path segment. srcDir anchors
+// the module-aware lookup; it must be the (absolute) directory of the file
+// being split, since go/build resolves relative to srcDir rather than the
+// process's current working directory. The bool result reports whether the
+// package was actually located; on false the returned name is only the
+// last-path-segment guess, which may not match what the code imports under.
+func resolvePackageName(importPath, srcDir string) (string, bool) {
+	if pkg, err := build.Import(importPath, srcDir, build.FindOnly); err == nil {
+		if info, err := build.ImportDir(pkg.Dir, 0); err == nil && info.Name != "" {
+			return info.Name, true
+		}
+	}
+
+	parts := strings.Split(importPath, "/")
+	return parts[len(parts)-1], false
+}
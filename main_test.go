@@ -0,0 +1,191 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func parseTestFile(t *testing.T, src string) *ast.File {
+	t.Helper()
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, "test.go", "package p\n\n"+src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("failed to parse test source: %v", err)
+	}
+	return node
+}
+
+func TestCyclomaticComplexity(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want int
+	}{
+		{
+			name: "straight line",
+			src:  "func F() {\n\tx := 1\n\t_ = x\n}",
+			want: 1,
+		},
+		{
+			name: "single if",
+			src:  "func F(x int) {\n\tif x > 0 {\n\t\treturn\n\t}\n}",
+			want: 2,
+		},
+		{
+			name: "if-else, for, and short-circuit",
+			src: "func F(x int, ok bool) {\n" +
+				"\tif x > 0 && ok {\n" +
+				"\t\treturn\n" +
+				"\t} else {\n" +
+				"\t\tfor i := 0; i < x; i++ {\n" +
+				"\t\t\t_ = i\n" +
+				"\t\t}\n" +
+				"\t}\n" +
+				"}",
+			want: 4,
+		},
+		{
+			name: "switch with two non-default cases",
+			src: "func F(x int) {\n" +
+				"\tswitch x {\n" +
+				"\tcase 1:\n" +
+				"\tcase 2:\n" +
+				"\tdefault:\n" +
+				"\t}\n" +
+				"}",
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := parseTestFile(t, tt.src)
+			fn := extractFunctions(node)[0]
+			if got := cyclomaticComplexity(fn); got != tt.want {
+				t.Errorf("cyclomaticComplexity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByDependencyKeepsMethodsWithReceiver(t *testing.T) {
+	node := parseTestFile(t, `
+type Widget struct{ n int }
+
+func (w *Widget) Inc() { w.n++ }
+
+func (w *Widget) Dec() { w.n-- }
+
+type Gadget struct{ n int }
+
+func (g *Gadget) Reset() { g.n = 0 }
+
+func Standalone() {}
+`)
+
+	typeDecls := extractTypeDecls(node)
+	functions := extractFunctions(node)
+
+	components := groupByDependency(typeDecls, functions)
+
+	rootOf := make(map[string]int)
+	for i, component := range components {
+		for _, n := range component {
+			for name := range n.names() {
+				rootOf[name] = i
+			}
+		}
+	}
+
+	if rootOf["Widget"] != rootOf["Inc"] || rootOf["Widget"] != rootOf["Dec"] {
+		t.Errorf("Widget and its methods landed in different components: %v", rootOf)
+	}
+	if rootOf["Widget"] == rootOf["Gadget"] {
+		t.Errorf("unrelated types Widget and Gadget were merged into one component")
+	}
+	if rootOf["Gadget"] != rootOf["Reset"] {
+		t.Errorf("Gadget and Reset landed in different components: %v", rootOf)
+	}
+}
+
+func TestPartitionGroupsBalancesBucketSizes(t *testing.T) {
+	components := [][]declGroupNode{
+		{{origIndex: 0}, {origIndex: 1}, {origIndex: 2}},
+		{{origIndex: 3}},
+		{{origIndex: 4}},
+	}
+
+	buckets := partitionGroups(components, 2)
+	if len(buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(buckets))
+	}
+
+	total := 0
+	for _, bucket := range buckets {
+		total += len(bucket)
+	}
+	if total != 5 {
+		t.Errorf("partitionGroups dropped nodes: got %d total, want 5", total)
+	}
+
+	// The 3-node component must stay whole in one bucket, forcing the other
+	// two single-node components together to balance it.
+	big, small := buckets[0], buckets[1]
+	if len(big) < len(small) {
+		big, small = small, big
+	}
+	if len(big) != 3 || len(small) != 2 {
+		t.Errorf("partitionGroups did not balance buckets: got sizes %d and %d", len(big), len(small))
+	}
+}
+
+func TestGenerateFilenameSuffixRejectsBuildConstrainedTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		exclude string // a term that must never be the returned suffix
+	}{
+		{
+			name: "test is rejected even as the only doc term",
+			src: `// test test test
+func F() {}`,
+			exclude: "test",
+		},
+		{
+			name: "a GOOS name is rejected",
+			src: `// linux linux linux
+func F() {}`,
+			exclude: "linux",
+		},
+		{
+			name: "a GOARCH name is rejected",
+			src: `// arm arm arm
+func F() {}`,
+			exclude: "arm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := parseTestFile(t, tt.src)
+			functions := extractFunctions(node)
+			suffix := generateFilenameSuffix(functions, nil, nil, map[string]bool{})
+			if suffix == tt.exclude {
+				t.Errorf("generateFilenameSuffix() = %q, which go build treats as a build constraint", suffix)
+			}
+		})
+	}
+}
+
+func TestGenerateFilenameSuffixPicksDocTerm(t *testing.T) {
+	node := parseTestFile(t, `// checkConn validates the connection; the connection must be live.
+func checkConn() {}`)
+	functions := extractFunctions(node)
+
+	suffix := generateFilenameSuffix(functions, nil, nil, map[string]bool{})
+	if suffix != "connection" {
+		t.Errorf("generateFilenameSuffix() = %q, want %q", suffix, "connection")
+	}
+}